@@ -0,0 +1,52 @@
+package httpstat
+
+import (
+	"context"
+	"errors"
+	"net/http/httptrace"
+	"testing"
+	"time"
+)
+
+// TestConnectAttemptRaceReportsWinnerDuration guards against a regression
+// where, on a dual-stack race between two dial attempts, a losing
+// attempt's earlier ConnectStart made TCPConnection report wall-clock
+// time since the loser started instead of the winning attempt's own
+// duration.
+func TestConnectAttemptRaceReportsWinnerDuration(t *testing.T) {
+	r := New()
+	ctx := WithHTTPStat(context.Background(), r)
+	trace := httptrace.ContextClientTrace(ctx)
+
+	trace.DNSStart(httptrace.DNSStartInfo{})
+	trace.DNSDone(httptrace.DNSDoneInfo{})
+
+	trace.ConnectStart("tcp4", "10.0.0.1:80")
+	time.Sleep(30 * time.Millisecond)
+	trace.ConnectStart("tcp6", "[::1]:80")
+	time.Sleep(10 * time.Millisecond)
+	trace.ConnectDone("tcp6", "[::1]:80", nil)
+	time.Sleep(10 * time.Millisecond)
+	trace.ConnectDone("tcp4", "10.0.0.1:80", errors.New("loser"))
+
+	if got := r.TCPConnection; got >= 25*time.Millisecond {
+		t.Fatalf("TCPConnection = %v, want roughly the winner's own ~10ms duration, not wall-clock since the loser started", got)
+	}
+
+	attempts := r.ConnectAttempts()
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 connect attempts, got %d", len(attempts))
+	}
+
+	var winners, losers int
+	for _, a := range attempts {
+		if a.Err == nil {
+			winners++
+		} else {
+			losers++
+		}
+	}
+	if winners != 1 || losers != 1 {
+		t.Fatalf("expected 1 winning and 1 losing attempt, got %d winners and %d losers", winners, losers)
+	}
+}
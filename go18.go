@@ -3,7 +3,9 @@ package httpstat
 import (
 	"context"
 	"crypto/tls"
+	"net"
 	"net/http/httptrace"
+	"net/textproto"
 	"time"
 )
 
@@ -11,6 +13,7 @@ import (
 // This must be called after reading response body.
 func (r *Result) Done(t time.Time) {
 	r.m.Lock()
+	defer r.m.Unlock()
 	r.transferDone = t
 
 	// This means result is empty (it does nothing).
@@ -21,7 +24,6 @@ func (r *Result) Done(t time.Time) {
 
 	r.contentTransfer = r.transferDone.Sub(r.transferStart)
 	r.total = r.transferDone.Sub(r.dnsStart)
-	r.m.Unlock()
 }
 
 // ContentTransfer returns the duration of content transfer time.
@@ -44,6 +46,162 @@ func (r *Result) Total(t time.Time) (d time.Duration) {
 	return
 }
 
+// RemoteAddr returns the address of the connection that served the
+// request, as reported by httptrace.GotConnInfo. It is nil until the
+// connection has been obtained.
+func (r *Result) RemoteAddr() (addr net.Addr) {
+	r.m.RLock()
+	addr = r.remoteAddr
+	r.m.RUnlock()
+	return
+}
+
+// Reused reports whether the connection was reused from a previous
+// request instead of being dialed fresh.
+func (r *Result) Reused() (reused bool) {
+	r.m.RLock()
+	reused = r.isReused
+	r.m.RUnlock()
+	return
+}
+
+// WasIdle reports whether the reused connection was sitting idle in the
+// pool before this request took it.
+func (r *Result) WasIdle() (idle bool) {
+	r.m.RLock()
+	idle = r.wasIdle
+	r.m.RUnlock()
+	return
+}
+
+// IdleTime returns how long the reused connection had been idle before
+// this request took it. It is zero when the connection was not reused.
+func (r *Result) IdleTime() (d time.Duration) {
+	r.m.RLock()
+	d = r.idleTime
+	r.m.RUnlock()
+	return
+}
+
+// Protocol returns the negotiated protocol for the connection, e.g.
+// "h2" or "http/1.1". It is only known for TLS connections whose
+// handshake was traced for this request, once it has completed; it is
+// empty otherwise, including for a reused connection.
+func (r *Result) Protocol() (proto string) {
+	r.m.RLock()
+	proto = r.protocol
+	r.m.RUnlock()
+	return
+}
+
+// DNS returns the outcome of the DNS lookup performed for the request.
+// It is the zero value until the lookup completes; for connections
+// dialed directly to an IP (no lookup) it stays zero.
+func (r *Result) DNS() (d DNSResult) {
+	r.m.RLock()
+	d = r.dns
+	r.m.RUnlock()
+	return
+}
+
+// ConnectAttempts returns every dial attempt made while establishing the
+// connection, in the order their ConnectDone fired. On dual-stack hosts
+// this may contain more than one entry. When the connection was
+// established, the winning attempt has a nil Err; when every attempt
+// failed, none has.
+func (r *Result) ConnectAttempts() []ConnectAttempt {
+	r.m.RLock()
+	attempts := make([]ConnectAttempt, len(r.connectAttempts))
+	copy(attempts, r.connectAttempts)
+	r.m.RUnlock()
+	return attempts
+}
+
+// HeaderWrite returns how long it took to write the request headers,
+// from the first header field to the last. On HTTP/2 connections this is
+// usually negligible; it matters mainly for requests with large header
+// blocks (e.g. big cookies or auth tokens).
+func (r *Result) HeaderWrite() (d time.Duration) {
+	r.m.RLock()
+	if !r.headerWriteStart.IsZero() && !r.headerWriteDone.IsZero() {
+		d = r.headerWriteDone.Sub(r.headerWriteStart)
+	}
+	r.m.RUnlock()
+	return
+}
+
+// Wait100Continue returns the time from when the client began waiting
+// for a "100 Continue" response to when the request, body included, had
+// been fully written; the time spent writing the body is part of it. It
+// is zero unless the request used "Expect: 100-continue".
+func (r *Result) Wait100Continue() (d time.Duration) {
+	r.m.RLock()
+	if !r.continueStart.IsZero() && !r.serverStart.IsZero() {
+		d = r.serverStart.Sub(r.continueStart)
+	}
+	r.m.RUnlock()
+	return
+}
+
+// EarlyHints returns, for every 1xx informational response received
+// before the final response (e.g. HTTP/2 "103 Early Hints"), how long
+// after the request started it arrived.
+func (r *Result) EarlyHints() []time.Duration {
+	r.m.RLock()
+	hints := make([]time.Duration, len(r.earlyHints))
+	copy(hints, r.earlyHints)
+	r.m.RUnlock()
+	return hints
+}
+
+// GetConnWait returns how long the client waited, between asking the
+// transport for a connection and actually getting one, before GotConn
+// fired. On HTTP/2, where many requests share one connection, this is
+// the time spent queued behind the transport's own concurrency limits
+// rather than any network activity, and is the piece request-level
+// DNS/TCP/TLS timings can't show for a reused stream.
+func (r *Result) GetConnWait() (d time.Duration) {
+	r.m.RLock()
+	if !r.getConnStart.IsZero() && !r.gotConnAt.IsZero() {
+		d = r.gotConnAt.Sub(r.getConnStart)
+	}
+	r.m.RUnlock()
+	return
+}
+
+// IsProxied reports whether the request was routed through an HTTP
+// proxy, as detected by DetectProxy. It is always false unless the
+// request's Transport was wrapped with DetectProxy.
+func (r *Result) IsProxied() (proxied bool) {
+	r.m.RLock()
+	proxied = r.isProxied
+	r.m.RUnlock()
+	return
+}
+
+// ProxyConnect returns the time spent dialing the proxy. It is zero
+// unless the request was proxied; for proxied requests this is what
+// TCPConnection measures.
+func (r *Result) ProxyConnect() (d time.Duration) {
+	r.m.RLock()
+	if r.isProxied {
+		d = r.TCPConnection
+	}
+	r.m.RUnlock()
+	return
+}
+
+// ProxyCONNECT returns the round trip time of the CONNECT request used
+// to establish the tunnel to the origin, i.e. the time between the
+// proxy dial completing and the origin TLS handshake starting. It is
+// zero unless the request was proxied.
+func (r *Result) ProxyCONNECT() (d time.Duration) {
+	r.m.RLock()
+	d = r.proxyCONNECT
+	r.m.RUnlock()
+	return
+}
+
 func withClientTrace(ctx context.Context, r *Result) context.Context {
 	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
 		DNSStart: func(i httptrace.DNSStartInfo) {
@@ -58,27 +216,61 @@ func withClientTrace(ctx context.Context, r *Result) context.Context {
 
 			r.DNSLookup = r.dnsDone.Sub(r.dnsStart)
 			r.NameLookup = r.dnsDone.Sub(r.dnsStart)
+			r.dns = DNSResult{Addrs: i.Addrs, Coalesced: i.Coalesced, Err: i.Err}
 			r.m.Unlock()
 		},
 
-		ConnectStart: func(_, _ string) {
+		// ConnectStart/ConnectDone may each fire more than once per request:
+		// on dual-stack hosts the transport races a v4 and a v6 dial (Happy
+		// Eyeballs), so attempts are tracked individually by network+addr
+		// rather than overwriting a single tcpStart/tcpDone pair.
+		ConnectStart: func(network, addr string) {
 			r.m.Lock()
-			r.tcpStart = time.Now()
+			now := time.Now()
+			if r.tcpStart.IsZero() {
+				r.tcpStart = now
+			}
 
 			// When connecting to IP (When no DNS lookup)
 			if r.dnsStart.IsZero() {
-				r.dnsStart = r.tcpStart
-				r.dnsDone = r.tcpStart
+				r.dnsStart = now
+				r.dnsDone = now
 			}
+
+			if r.connecting == nil {
+				r.connecting = make(map[string]time.Time)
+			}
+			r.connecting[network+" "+addr] = now
 			r.m.Unlock()
 		},
 
 		ConnectDone: func(network, addr string, err error) {
 			r.m.Lock()
-			r.tcpDone = time.Now()
+			now := time.Now()
+
+			key := network + " " + addr
+			start := r.connecting[key]
+			if start.IsZero() {
+				start = now
+			}
+			delete(r.connecting, key)
+
+			r.connectAttempts = append(r.connectAttempts, ConnectAttempt{
+				Network: network,
+				Addr:    addr,
+				Start:   start,
+				End:     now,
+				Err:     err,
+			})
 
-			r.TCPConnection = r.tcpDone.Sub(r.tcpStart)
-			r.Connect = r.tcpDone.Sub(r.dnsStart)
+			// Only the winning attempt should move TCPConnection/Connect;
+			// a losing attempt reporting its failure after the winner has
+			// already connected must not clobber the real timing.
+			if err == nil {
+				r.tcpDone = now
+				r.TCPConnection = now.Sub(start)
+				r.Connect = now.Sub(r.dnsStart)
+			}
 			r.m.Unlock()
 		},
 
@@ -86,26 +278,46 @@ func withClientTrace(ctx context.Context, r *Result) context.Context {
 			r.m.Lock()
 			r.isTLS = true
 			r.tlsStart = time.Now()
+
+			// When the transport went through a proxy, TLSHandshakeStart
+			// fires only after the CONNECT tunnel is established, so the
+			// gap since ConnectDone is the CONNECT round trip rather than
+			// part of the origin TLS handshake.
+			if r.isProxied && !r.tcpDone.IsZero() {
+				r.proxyCONNECT = r.tlsStart.Sub(r.tcpDone)
+			}
 			r.m.Unlock()
 		},
 
-		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+		TLSHandshakeDone: func(cs tls.ConnectionState, _ error) {
 			r.m.Lock()
 			r.tlsDone = time.Now()
 
 			r.TLSHandshake = r.tlsDone.Sub(r.tlsStart)
 			r.Pretransfer = r.tlsDone.Sub(r.dnsStart)
+			r.protocol = cs.NegotiatedProtocol
+			r.m.Unlock()
+		},
+
+		GetConn: func(_ string) {
+			r.m.Lock()
+			r.getConnStart = time.Now()
 			r.m.Unlock()
 		},
 
 		GotConn: func(i httptrace.GotConnInfo) {
+			r.m.Lock()
+			r.gotConnAt = time.Now()
+			r.remoteAddr = i.Conn.RemoteAddr()
+
 			// Handle when keep alive is used and connection is reused.
 			// DNSStart(Done) and ConnectStart(Done) is skipped
 			if i.Reused {
-				r.m.Lock()
 				r.isReused = true
-				r.m.Unlock()
+				r.wasIdle = i.WasIdle
+				r.idleTime = i.IdleTime
 			}
+			r.m.Unlock()
 		},
 
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
@@ -153,5 +365,32 @@ func withClientTrace(ctx context.Context, r *Result) context.Context {
 			r.transferStart = r.serverDone
 			r.m.Unlock()
 		},
+
+		WroteHeaderField: func(_ string, _ []string) {
+			r.m.Lock()
+			if r.headerWriteStart.IsZero() {
+				r.headerWriteStart = time.Now()
+			}
+			r.m.Unlock()
+		},
+
+		WroteHeaders: func() {
+			r.m.Lock()
+			r.headerWriteDone = time.Now()
+			r.m.Unlock()
+		},
+
+		Wait100Continue: func() {
+			r.m.Lock()
+			r.continueStart = time.Now()
+			r.m.Unlock()
+		},
+
+		Got1xxResponse: func(_ int, _ textproto.MIMEHeader) error {
+			r.m.Lock()
+			r.earlyHints = append(r.earlyHints, time.Since(r.dnsStart))
+			r.m.Unlock()
+			return nil
+		},
 	})
 }
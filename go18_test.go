@@ -0,0 +1,57 @@
+package httpstat
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"testing"
+	"time"
+)
+
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
+
+type fakeConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c fakeConn) RemoteAddr() net.Addr { return c.remote }
+
+// TestGotConnAndTLSHandshakeDonePopulateAccessors guards against a
+// regression where RemoteAddr/Reused/WasIdle/IdleTime/Protocol were
+// wired up but never actually exercised against the httptrace values
+// they're meant to reflect.
+func TestGotConnAndTLSHandshakeDonePopulateAccessors(t *testing.T) {
+	r := New()
+	ctx := WithHTTPStat(context.Background(), r)
+	trace := httptrace.ContextClientTrace(ctx)
+
+	trace.GotConn(httptrace.GotConnInfo{
+		Conn:     fakeConn{remote: fakeAddr("203.0.113.1:443")},
+		Reused:   true,
+		WasIdle:  true,
+		IdleTime: 2 * time.Second,
+	})
+	trace.TLSHandshakeStart()
+	trace.TLSHandshakeDone(tls.ConnectionState{NegotiatedProtocol: "h2"}, nil)
+
+	if got := r.RemoteAddr(); got == nil || got.String() != "203.0.113.1:443" {
+		t.Fatalf("RemoteAddr() = %v, want 203.0.113.1:443", got)
+	}
+	if !r.Reused() {
+		t.Fatal("Reused() = false, want true")
+	}
+	if !r.WasIdle() {
+		t.Fatal("WasIdle() = false, want true")
+	}
+	if got := r.IdleTime(); got != 2*time.Second {
+		t.Fatalf("IdleTime() = %v, want 2s", got)
+	}
+	if got := r.Protocol(); got != "h2" {
+		t.Fatalf("Protocol() = %q, want h2", got)
+	}
+}
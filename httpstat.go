@@ -0,0 +1,103 @@
+package httpstat
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Result holds the timing information from a single HTTP round trip, as
+// captured via httptrace.ClientTrace. Every exported Duration field is set
+// once the corresponding trace hook has fired; fields are zero until then.
+type Result struct {
+	DNSLookup        time.Duration
+	TCPConnection    time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration
+
+	NameLookup    time.Duration
+	Connect       time.Duration
+	Pretransfer   time.Duration
+	StartTransfer time.Duration
+
+	isTLS    bool
+	isReused bool
+
+	remoteAddr net.Addr
+	wasIdle    bool
+	idleTime   time.Duration
+	protocol   string
+
+	dns             DNSResult
+	connecting      map[string]time.Time
+	connectAttempts []ConnectAttempt
+
+	headerWriteStart time.Time
+	headerWriteDone  time.Time
+	continueStart    time.Time
+	earlyHints       []time.Duration
+
+	isProxied    bool
+	proxyCONNECT time.Duration
+
+	getConnStart time.Time
+	gotConnAt    time.Time
+
+	dnsStart      time.Time
+	dnsDone       time.Time
+	tcpStart      time.Time
+	tcpDone       time.Time
+	tlsStart      time.Time
+	tlsDone       time.Time
+	serverStart   time.Time
+	serverDone    time.Time
+	transferStart time.Time
+	transferDone  time.Time
+
+	contentTransfer time.Duration
+	total           time.Duration
+
+	m sync.RWMutex
+}
+
+// DNSResult holds the outcome of the DNS lookup performed for a request,
+// as reported by httptrace.DNSDoneInfo.
+type DNSResult struct {
+	Addrs     []net.IPAddr
+	Coalesced bool
+	Err       error
+}
+
+// ConnectAttempt records one dial attempt made while establishing the
+// connection used for a request. On dual-stack hosts the transport may
+// race several of these (Happy Eyeballs); only one of them wins.
+type ConnectAttempt struct {
+	Network string
+	Addr    string
+	Start   time.Time
+	End     time.Time
+	Err     error
+}
+
+// New returns an empty Result ready to be passed to WithHTTPStat.
+func New() *Result {
+	return &Result{}
+}
+
+type resultKey struct{}
+
+// WithHTTPStat returns a context based on ctx that traces the HTTP round
+// trip and records the result in r. Pass the returned context to an
+// *http.Request (req.WithContext) before sending it.
+func WithHTTPStat(ctx context.Context, r *Result) context.Context {
+	ctx = context.WithValue(ctx, resultKey{}, r)
+	return withClientTrace(ctx, r)
+}
+
+// ResultFromContext returns the Result attached to ctx by WithHTTPStat,
+// if any.
+func ResultFromContext(ctx context.Context) (*Result, bool) {
+	r, ok := ctx.Value(resultKey{}).(*Result)
+	return r, ok
+}
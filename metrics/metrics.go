@@ -0,0 +1,136 @@
+// Package metrics turns httpstat.Result values into Prometheus
+// observations, so callers get per-phase latency dashboards without
+// having to plumb a *httpstat.Result through their own code.
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/PathDNA/go-httpstat"
+)
+
+var labelNames = []string{"host", "scheme", "method", "status", "proto", "reused"}
+
+// Metrics holds the histograms for every phase tracked by httpstat.Result.
+type Metrics struct {
+	dnsLookup        *prometheus.HistogramVec
+	tcpConnect       *prometheus.HistogramVec
+	tlsHandshake     *prometheus.HistogramVec
+	serverProcessing *prometheus.HistogramVec
+	contentTransfer  *prometheus.HistogramVec
+	total            *prometheus.HistogramVec
+}
+
+// New creates the httpstat histograms and registers them with reg. Pass
+// nil to use prometheus.DefaultRegisterer.
+func New(reg prometheus.Registerer) *Metrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	histogram := func(name, help string) *prometheus.HistogramVec {
+		hv := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "httpstat",
+			Name:      name,
+			Help:      help,
+			Buckets:   prometheus.DefBuckets,
+		}, labelNames)
+		reg.MustRegister(hv)
+		return hv
+	}
+
+	return &Metrics{
+		dnsLookup:        histogram("dns_lookup_seconds", "Time spent performing the DNS lookup."),
+		tcpConnect:       histogram("tcp_connect_seconds", "Time spent establishing the TCP connection."),
+		tlsHandshake:     histogram("tls_handshake_seconds", "Time spent on the TLS handshake."),
+		serverProcessing: histogram("server_processing_seconds", "Time from request written to first response byte."),
+		contentTransfer:  histogram("content_transfer_seconds", "Time spent reading the response body."),
+		total:            histogram("total_seconds", "Total time from DNS lookup start to response body close."),
+	}
+}
+
+func (m *Metrics) observe(r *httpstat.Result, labels prometheus.Labels, now time.Time) {
+	m.dnsLookup.With(labels).Observe(r.DNSLookup.Seconds())
+	m.tcpConnect.With(labels).Observe(r.TCPConnection.Seconds())
+	m.tlsHandshake.With(labels).Observe(r.TLSHandshake.Seconds())
+	m.serverProcessing.With(labels).Observe(r.ServerProcessing.Seconds())
+	m.contentTransfer.With(labels).Observe(r.ContentTransfer(now).Seconds())
+	m.total.With(labels).Observe(r.Total(now).Seconds())
+}
+
+// WrapTransport wraps base (http.DefaultTransport if nil) with a
+// RoundTripper that traces every request with httpstat and records the
+// result on m's histograms once the response body is closed. Reuse the
+// same *Metrics across as many transports as you like, e.g. one per
+// upstream service -- it's New that performs the Prometheus
+// registration, so calling it more than once against the same
+// Registerer panics with a duplicate registration error.
+func (m *Metrics) WrapTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{base: base, m: m}
+}
+
+// WrapTransport is a convenience for the common case of a single traced
+// transport: it creates a Metrics registered with reg and wraps base
+// with it in one call. Wrapping a second transport against the same
+// Registerer this way panics on duplicate registration; call New once
+// and use (*Metrics).WrapTransport for each transport instead.
+func WrapTransport(base http.RoundTripper, reg prometheus.Registerer) http.RoundTripper {
+	return New(reg).WrapTransport(base)
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+	m    *Metrics
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	result := httpstat.New()
+	traced := req.WithContext(httpstat.WithHTTPStat(req.Context(), result))
+
+	resp, err := rt.base.RoundTrip(traced)
+	if err != nil {
+		return resp, err
+	}
+
+	labels := prometheus.Labels{
+		"host":   req.URL.Host,
+		"scheme": req.URL.Scheme,
+		"method": req.Method,
+		"status": strconv.Itoa(resp.StatusCode),
+		"proto":  resp.Proto,
+		"reused": strconv.FormatBool(result.Reused()),
+	}
+
+	resp.Body = &observingBody{ReadCloser: resp.Body, result: result, m: rt.m, labels: labels}
+	return resp, nil
+}
+
+// observingBody records the httpstat observations once the response body
+// is closed, matching the package convention that Done must be called
+// after reading the body.
+type observingBody struct {
+	io.ReadCloser
+	result *httpstat.Result
+	m      *Metrics
+	labels prometheus.Labels
+	once   sync.Once
+}
+
+func (b *observingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		now := time.Now()
+		b.result.Done(now)
+		b.m.observe(b.result, b.labels, now)
+	})
+	return err
+}
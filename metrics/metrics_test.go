@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricsSharedAcrossTransports(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("wrapping a second transport with the same *Metrics panicked: %v", r)
+		}
+	}()
+
+	m.WrapTransport(nil)
+	m.WrapTransport(nil)
+}
+
+func TestWrapTransportTwicePanicsOnDuplicateRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected calling WrapTransport twice against the same Registerer to panic")
+		}
+	}()
+
+	WrapTransport(nil, reg)
+	WrapTransport(nil, reg)
+}
@@ -0,0 +1,39 @@
+package httpstat
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// DetectProxy returns a shallow copy of base whose Proxy func marks
+// whichever Result is attached to each request's context (via
+// WithHTTPStat) as proxied, whenever it resolves a proxy URL for that
+// request.
+//
+// Call DetectProxy once when you build the Transport and reuse the
+// returned value for every request, the same way you'd reuse base
+// itself: Transport.Clone only deep-copies exported fields, so a fresh
+// clone per request would start with an empty connection pool and pay a
+// new proxy dial, CONNECT and origin TLS handshake on every request,
+// defeating keep-alives for exactly the traffic this is meant to
+// diagnose.
+func DetectProxy(base *http.Transport) *http.Transport {
+	proxy := base.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	t := base.Clone()
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req)
+		if err == nil && u != nil {
+			if r, ok := ResultFromContext(req.Context()); ok {
+				r.m.Lock()
+				r.isProxied = true
+				r.m.Unlock()
+			}
+		}
+		return u, err
+	}
+	return t
+}
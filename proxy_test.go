@@ -0,0 +1,43 @@
+package httpstat
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"testing"
+	"time"
+)
+
+// TestProxyConnectAndCONNECTAreIsolatedFromTLSHandshake guards against a
+// regression where, for a proxied request, the CONNECT tunnel round
+// trip and the origin TLS handshake that follows it could bleed into
+// each other: ProxyConnect must stay the proxy dial time, ProxyCONNECT
+// the gap between the dial finishing and the origin handshake starting,
+// and TLSHandshake only the handshake itself.
+func TestProxyConnectAndCONNECTAreIsolatedFromTLSHandshake(t *testing.T) {
+	r := New()
+	r.isProxied = true
+
+	ctx := WithHTTPStat(context.Background(), r)
+	trace := httptrace.ContextClientTrace(ctx)
+
+	trace.ConnectStart("tcp", "proxy.invalid:3128")
+	time.Sleep(10 * time.Millisecond)
+	trace.ConnectDone("tcp", "proxy.invalid:3128", nil)
+
+	time.Sleep(20 * time.Millisecond)
+
+	trace.TLSHandshakeStart()
+	time.Sleep(15 * time.Millisecond)
+	trace.TLSHandshakeDone(tls.ConnectionState{}, nil)
+
+	if got := r.ProxyConnect(); got < 5*time.Millisecond || got >= 15*time.Millisecond {
+		t.Fatalf("ProxyConnect() = %v, want roughly the ~10ms proxy dial", got)
+	}
+	if got := r.ProxyCONNECT(); got < 15*time.Millisecond || got >= 30*time.Millisecond {
+		t.Fatalf("ProxyCONNECT() = %v, want roughly the ~20ms gap between the dial finishing and the TLS handshake starting", got)
+	}
+	if got := r.TLSHandshake; got < 10*time.Millisecond || got >= 25*time.Millisecond {
+		t.Fatalf("TLSHandshake = %v, want roughly the ~15ms handshake duration, not inflated by the CONNECT round trip", got)
+	}
+}
@@ -0,0 +1,237 @@
+package httpstat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonResult is the stable wire format for Result. Durations are
+// nanoseconds so consumers don't have to parse Go's Duration strings.
+type jsonResult struct {
+	DNSLookup        int64 `json:"dns_lookup_ns"`
+	TCPConnection    int64 `json:"tcp_connection_ns"`
+	TLSHandshake     int64 `json:"tls_handshake_ns"`
+	ServerProcessing int64 `json:"server_processing_ns"`
+	ContentTransfer  int64 `json:"content_transfer_ns"`
+
+	NameLookup    int64 `json:"name_lookup_ns"`
+	Connect       int64 `json:"connect_ns"`
+	Pretransfer   int64 `json:"pretransfer_ns"`
+	StartTransfer int64 `json:"start_transfer_ns"`
+	Total         int64 `json:"total_ns"`
+
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Reused     bool   `json:"reused"`
+	WasIdle    bool   `json:"was_idle"`
+	IdleTime   int64  `json:"idle_time_ns"`
+	Protocol   string `json:"protocol,omitempty"`
+
+	Proxied      bool  `json:"proxied"`
+	ProxyConnect int64 `json:"proxy_connect_ns,omitempty"`
+	ProxyCONNECT int64 `json:"proxy_connect_rtt_ns,omitempty"`
+
+	DNS             jsonDNSResult        `json:"dns"`
+	ConnectAttempts []jsonConnectAttempt `json:"connect_attempts,omitempty"`
+
+	HeaderWrite     int64   `json:"header_write_ns,omitempty"`
+	Wait100Continue int64   `json:"wait_100_continue_ns,omitempty"`
+	EarlyHints      []int64 `json:"early_hints_ns,omitempty"`
+	GetConnWait     int64   `json:"get_conn_wait_ns,omitempty"`
+}
+
+// jsonDNSResult mirrors DNSResult for marshaling: net.IPAddr has no
+// stable JSON form of its own and error values don't marshal at all.
+type jsonDNSResult struct {
+	Addrs     []string `json:"addrs,omitempty"`
+	Coalesced bool     `json:"coalesced"`
+	Err       string   `json:"err,omitempty"`
+}
+
+// jsonConnectAttempt mirrors ConnectAttempt for marshaling, collapsing
+// Start/End into the duration consumers actually want.
+type jsonConnectAttempt struct {
+	Network  string `json:"network"`
+	Addr     string `json:"addr"`
+	Duration int64  `json:"duration_ns"`
+	Err      string `json:"err,omitempty"`
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// MarshalJSON implements json.Marshaler. Call Result.Done before
+// marshaling so ContentTransfer/Total reflect the finished request.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	var addr string
+	if r.remoteAddr != nil {
+		addr = r.remoteAddr.String()
+	}
+
+	dnsAddrs := make([]string, len(r.dns.Addrs))
+	for i, a := range r.dns.Addrs {
+		dnsAddrs[i] = a.String()
+	}
+
+	attempts := make([]jsonConnectAttempt, len(r.connectAttempts))
+	for i, a := range r.connectAttempts {
+		attempts[i] = jsonConnectAttempt{
+			Network:  a.Network,
+			Addr:     a.Addr,
+			Duration: a.End.Sub(a.Start).Nanoseconds(),
+			Err:      errString(a.Err),
+		}
+	}
+
+	var headerWrite int64
+	if !r.headerWriteStart.IsZero() && !r.headerWriteDone.IsZero() {
+		headerWrite = r.headerWriteDone.Sub(r.headerWriteStart).Nanoseconds()
+	}
+
+	var wait100Continue int64
+	if !r.continueStart.IsZero() && !r.serverStart.IsZero() {
+		wait100Continue = r.serverStart.Sub(r.continueStart).Nanoseconds()
+	}
+
+	earlyHints := make([]int64, len(r.earlyHints))
+	for i, d := range r.earlyHints {
+		earlyHints[i] = d.Nanoseconds()
+	}
+
+	var getConnWait int64
+	if !r.getConnStart.IsZero() && !r.gotConnAt.IsZero() {
+		getConnWait = r.gotConnAt.Sub(r.getConnStart).Nanoseconds()
+	}
+
+	return json.Marshal(jsonResult{
+		DNSLookup:        r.DNSLookup.Nanoseconds(),
+		TCPConnection:    r.TCPConnection.Nanoseconds(),
+		TLSHandshake:     r.TLSHandshake.Nanoseconds(),
+		ServerProcessing: r.ServerProcessing.Nanoseconds(),
+		ContentTransfer:  r.contentTransfer.Nanoseconds(),
+
+		NameLookup:    r.NameLookup.Nanoseconds(),
+		Connect:       r.Connect.Nanoseconds(),
+		Pretransfer:   r.Pretransfer.Nanoseconds(),
+		StartTransfer: r.StartTransfer.Nanoseconds(),
+		Total:         r.total.Nanoseconds(),
+
+		RemoteAddr: addr,
+		Reused:     r.isReused,
+		WasIdle:    r.wasIdle,
+		IdleTime:   r.idleTime.Nanoseconds(),
+		Protocol:   r.protocol,
+
+		Proxied:      r.isProxied,
+		ProxyConnect: proxyConnectNanos(r.isProxied, r.TCPConnection),
+		ProxyCONNECT: r.proxyCONNECT.Nanoseconds(),
+
+		DNS: jsonDNSResult{
+			Addrs:     dnsAddrs,
+			Coalesced: r.dns.Coalesced,
+			Err:       errString(r.dns.Err),
+		},
+		ConnectAttempts: attempts,
+
+		HeaderWrite:     headerWrite,
+		Wait100Continue: wait100Continue,
+		EarlyHints:      earlyHints,
+		GetConnWait:     getConnWait,
+	})
+}
+
+func proxyConnectNanos(proxied bool, tcpConnection time.Duration) int64 {
+	if !proxied {
+		return 0
+	}
+	return tcpConnection.Nanoseconds()
+}
+
+// CSVHeader names the columns written by WriteCSV, in order. Write it
+// once per file before logging individual results.
+var CSVHeader = []string{
+	"dns_lookup_ns", "tcp_connection_ns", "tls_handshake_ns",
+	"server_processing_ns", "content_transfer_ns", "total_ns",
+	"remote_addr", "reused", "protocol",
+}
+
+// WriteCSV appends one row describing r to w, in the column order given
+// by CSVHeader. Call Result.Done before WriteCSV so content_transfer_ns
+// and total_ns are populated.
+func (r *Result) WriteCSV(w io.Writer) error {
+	r.m.RLock()
+	addr := ""
+	if r.remoteAddr != nil {
+		addr = r.remoteAddr.String()
+	}
+	row := []string{
+		strconv.FormatInt(r.DNSLookup.Nanoseconds(), 10),
+		strconv.FormatInt(r.TCPConnection.Nanoseconds(), 10),
+		strconv.FormatInt(r.TLSHandshake.Nanoseconds(), 10),
+		strconv.FormatInt(r.ServerProcessing.Nanoseconds(), 10),
+		strconv.FormatInt(r.contentTransfer.Nanoseconds(), 10),
+		strconv.FormatInt(r.total.Nanoseconds(), 10),
+		addr,
+		strconv.FormatBool(r.isReused),
+		r.protocol,
+	}
+	r.m.RUnlock()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Format renders template with curl -w style tokens substituted for the
+// matching phase of r, giving parity with curl's timing report. Call
+// Result.Done before Format so %{time_total} is populated. Supported
+// tokens: %{time_namelookup}, %{time_connect}, %{time_appconnect},
+// %{time_starttransfer}, %{time_total}, %{remote_ip}, %{http_version}.
+func (r *Result) Format(template string) string {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.6f", d.Seconds())
+	}
+
+	remoteIP := ""
+	if r.remoteAddr != nil {
+		if host, _, err := net.SplitHostPort(r.remoteAddr.String()); err == nil {
+			remoteIP = host
+		} else {
+			remoteIP = r.remoteAddr.String()
+		}
+	}
+
+	httpVersion := r.protocol
+	if httpVersion == "" {
+		httpVersion = "http/1.1"
+	}
+
+	replacer := strings.NewReplacer(
+		"%{time_namelookup}", seconds(r.NameLookup),
+		"%{time_connect}", seconds(r.Connect),
+		"%{time_appconnect}", seconds(r.Pretransfer),
+		"%{time_starttransfer}", seconds(r.StartTransfer),
+		"%{time_total}", seconds(r.total),
+		"%{remote_ip}", remoteIP,
+		"%{http_version}", httpVersion,
+	)
+	return replacer.Replace(template)
+}
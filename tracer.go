@@ -0,0 +1,98 @@
+package httpstat
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tracer installs httpstat tracing on every request made through an
+// *http.Client, without requiring callers to thread a *Result through
+// their own code. This matters most for HTTP/2, where many requests
+// share one TCP+TLS connection: a single Result captured up front would
+// report DNS/TCP/TLS for whichever request happened to dial the
+// connection and zeroes for the rest, so Tracer instead keeps one
+// Result per round trip, keyed by the *http.Request that produced it.
+type Tracer struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	results map[*http.Request]*Result
+}
+
+// NewTracer wraps base (http.DefaultTransport if nil) with a
+// RoundTripper that traces every request it sends.
+func NewTracer(base http.RoundTripper) *Tracer {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Tracer{base: base, results: make(map[*http.Request]*Result)}
+}
+
+// Attach installs a Tracer as c's Transport and returns it.
+func Attach(c *http.Client) *Tracer {
+	t := NewTracer(c.Transport)
+	c.Transport = t
+	return t
+}
+
+// Result returns the Result recorded for req, if any. Fetch it as soon
+// as RoundTrip returns rather than after reading the response body:
+// Tracer only holds the entry for the lifetime of the round trip and
+// discards it once the response body is closed (or the round trip
+// fails), so a long-lived Client attached once doesn't leak an entry
+// per request for the life of the process.
+func (t *Tracer) Result(req *http.Request) (r *Result, ok bool) {
+	t.mu.Lock()
+	r, ok = t.results[req]
+	t.mu.Unlock()
+	return
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := New()
+	traced := req.WithContext(WithHTTPStat(req.Context(), r))
+
+	t.mu.Lock()
+	t.results[req] = r
+	t.mu.Unlock()
+
+	resp, err := t.base.RoundTrip(traced)
+	if err != nil {
+		t.mu.Lock()
+		delete(t.results, req)
+		t.mu.Unlock()
+
+		r.Done(time.Now())
+		return resp, err
+	}
+
+	resp.Body = &tracedBody{ReadCloser: resp.Body, result: r, tracer: t, req: req}
+	return resp, nil
+}
+
+// tracedBody calls Done on the underlying Result once the response body
+// is closed, matching the package convention that Done must be called
+// after reading the body, and evicts the Result from the owning
+// Tracer's map so it doesn't leak.
+type tracedBody struct {
+	io.ReadCloser
+	result *Result
+	tracer *Tracer
+	req    *http.Request
+	once   sync.Once
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.result.Done(time.Now())
+
+		b.tracer.mu.Lock()
+		delete(b.tracer.results, b.req)
+		b.tracer.mu.Unlock()
+	})
+	return err
+}
@@ -0,0 +1,82 @@
+package httpstat
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTracerEvictsResultOnError(t *testing.T) {
+	tr := NewTracer(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("boom")
+	}))
+
+	req, _ := http.NewRequest("GET", "http://example.invalid", nil)
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Fatal("expected RoundTrip to return the base transport's error")
+	}
+
+	if _, ok := tr.Result(req); ok {
+		t.Fatal("Result entry should have been evicted after a failed round trip")
+	}
+	if len(tr.results) != 0 {
+		t.Fatalf("tracer map should be empty after a failed round trip, has %d entries", len(tr.results))
+	}
+}
+
+func TestTracerEvictsResultOnBodyClose(t *testing.T) {
+	tr := NewTracer(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	}))
+
+	req, _ := http.NewRequest("GET", "http://example.invalid", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tr.Result(req); !ok {
+		t.Fatal("expected a Result to be recorded while the round trip is in flight")
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tr.Result(req); ok {
+		t.Fatal("Result entry should have been evicted once the body was closed")
+	}
+	if len(tr.results) != 0 {
+		t.Fatalf("tracer map should be empty after Close, has %d entries", len(tr.results))
+	}
+}
+
+// TestDoneOnUntracedResultDoesNotDeadlock guards against a regression
+// where Done returned early (dnsStart still zero, e.g. a RoundTrip that
+// failed before any trace hook fired) without releasing the lock it had
+// just taken, permanently deadlocking every later call on that Result.
+func TestDoneOnUntracedResultDoesNotDeadlock(t *testing.T) {
+	r := New()
+	r.Done(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		r.Reused()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Done left the Result locked")
+	}
+}